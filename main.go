@@ -1,230 +1,30 @@
 package main
 
 import (
-	"bytes"
-	"encoding/binary"
-	"encoding/hex"
 	"flag"
 	"fmt"
-	"hash/crc32"
-	"io"
 	"log"
 	"os"
-	"strconv"
-)
-
-// const pngImage string = "test.png"
-
-const IENDchunk string = "IEND"
-
-// PngHeader
-type PngHeader struct {
-	Header uint64
-}
-
-// ReadHeader
-func (p *PngHeader) ReadHeader(r io.Reader) error {
-	return binary.Read(r, binary.BigEndian, &p.Header)
-}
-
-// Validate
-func (p *PngHeader) Validate() (bool, error) {
-	if p.Header == 0 {
-		return false, nil
-	}
-
-	h := strconv.FormatUint(p.Header, 16)
-
-	data, err := hex.DecodeString(h)
-	if err != nil {
-		return false, err
-	}
-
-	if string(data[1:4]) == "PNG" {
-		return true, nil
-	}
-
-	return false, nil
-}
-
-// ChunkType
-type ChunkType uint32
-
-// String
-func (c ChunkType) String() (string, error) {
-	data, err := hex.DecodeString(strconv.FormatUint(uint64(c), 16))
-	if err != nil {
-		return "", err
-	}
-
-	return string(data), nil
-}
-
-// strToInt
-func strToInt(n string) uint32 {
-	return binary.BigEndian.Uint32([]byte(n))
-}
-
-// PngMetadata structure that holds png chunk fields.
-type PngMetadata struct {
-	Length uint32
-	Type   ChunkType
-	Data   []byte
-	CRC    uint32
-}
-
-const NewChunkTypeName string = "pUNK"
-
-// NewPngMetadata create a new png chunk function accept data as []byte and
-// returns pointer to PngMetadata or an error.
-func NewPngMetadata(data []byte) (*PngMetadata, error) {
-	chunk := &PngMetadata{
-		Length: uint32(len(data)),
-		Type:   ChunkType(strToInt(NewChunkTypeName)),
-		Data:   data,
-	}
-	if err := chunk.generateCRC(); err != nil {
-		return nil, err
-	}
-
-	return chunk, nil
-}
-
-// generateCRC generates a new CRC for the new png chunk.
-func (m *PngMetadata) generateCRC() error {
-	buffer := &bytes.Buffer{}
-	if err := binary.Write(buffer, binary.BigEndian, m.Type); err != nil {
-		return err
-	}
-
-	if err := binary.Write(buffer, binary.BigEndian, m.Data); err != nil {
-		return err
-	}
-
-	m.CRC = crc32.ChecksumIEEE(buffer.Bytes())
-	return nil
-}
-
-func (m *PngMetadata) readLength(r io.Reader) error {
-	return binary.Read(r, binary.BigEndian, &m.Length)
-}
-
-func (m *PngMetadata) readType(r io.Reader) error {
-	return binary.Read(r, binary.BigEndian, &m.Type)
-}
-
-func (m *PngMetadata) readData(r io.Reader) error {
-	buff := make([]byte, m.Length)
-	if _, err := r.Read(buff); err != nil {
-		return err
-	}
-	m.Data = buff
-
-	return nil
-}
-
-func (m *PngMetadata) readCRC(r io.Reader) error {
-	return binary.Read(r, binary.BigEndian, &m.CRC)
-}
-
-// PNG represent a png image.
-type PNG struct {
-	Header PngHeader
-	Chunks []*PngMetadata
-
-	newChunkIndex int
-}
-
-// ReadChunks reads all the PNG chunks.
-func (p *PNG) ReadChunks(r io.Reader) error {
-
-	stop := false
-	counter := 0
-	for {
-
-		metadata := new(PngMetadata)
-		if err := metadata.readLength(r); err != nil {
-			return err
-		}
-
-		if err := metadata.readType(r); err != nil {
-			return err
-		}
-
-		typAsStr, err := metadata.Type.String()
-		if err != nil {
-			return err
-		}
-
-		if typAsStr == IENDchunk {
-			stop = true
-		}
-
-		if err := metadata.readData(r); err != nil {
-			return err
-		}
-
-		if err := metadata.readCRC(r); err != nil {
-			return err
-		}
-
-		p.Chunks = append(p.Chunks, metadata)
-		if stop {
-			p.newChunkIndex = counter - 1
-			return nil
-		}
-
-		counter++
-
-	}
-
-	return nil
-}
-
-func (p *PNG) PrintChunks(seek io.Writer) {
-	for _, n := range p.Chunks {
-		typ, _ := n.Type.String()
-		fmt.Fprintf(seek, "--------------\n")
-		fmt.Fprintf(seek, "Length %v\n", n.Length)
-		fmt.Fprintf(seek, "Type %v\n", typ)
-		fmt.Fprintf(seek, "CRC %v\n", n.CRC)
-	}
-}
-
-// Marshal Chunks method used to add a new png chunk into the original one.
-func (p *PNG) Marshal() ([]byte, error) {
+	"syscall"
 
-	buff := new(bytes.Buffer)
-
-	if err := binary.Write(buff, binary.BigEndian, p.Header.Header); err != nil {
-		return nil, err
-	}
-
-	for _, n := range p.Chunks {
-		if err := binary.Write(buff, binary.BigEndian, n.Length); err != nil {
-			return nil, err
-		}
-		if err := binary.Write(buff, binary.BigEndian, uint32(n.Type)); err != nil {
-			return nil, err
-		}
-		if err := binary.Write(buff, binary.BigEndian, n.Data); err != nil {
-			return nil, err
-		}
-		if err := binary.Write(buff, binary.BigEndian, n.CRC); err != nil {
-			return nil, err
-		}
-	}
-
-	return buff.Bytes(), nil
-}
+	"github.com/t4ke0/stegno/pkg/png"
+	"golang.org/x/term"
+)
 
 func main() {
 	// you can use the tool in order to store a message into a png image or
-	// file data . for the moment we don't support encryption of the data that
-	// we are hiding into the png image but that will come in the newer
-	// versions.
-	// ./stegno --encode --png <image path> --to <out file>  --data <message> or --file <file path>
-	// ./stegno --decode --png <image path> --to <file path> or --dump true
+	// file data. Pass --password (or --password-stdin to avoid it showing up
+	// in your shell history) to encrypt the payload with AES-256-GCM using a
+	// key derived from the password via scrypt. Pass --mode lsb to hide the
+	// payload in the image's pixel bytes instead of an ancillary chunk, so it
+	// survives tools that strip unknown chunks; --capacity reports how many
+	// bytes that mode can hold for a given image. --verify walks every chunk
+	// and reports CRC corruption; add --repair to recompute CRCs on write.
+	// --sign-key <pem> signs the embedded payload with ECDSA-P256 into a
+	// `pSIG` chunk; --verify-key <pem> verifies it on decode.
+	// ./stegno --encode --png <image path> --to <out file>  --data <message> or --file <file path> [--password <pwd>] [--mode lsb|chunk]
+	// ./stegno --decode --png <image path> --to <file path> or --dump true [--password <pwd>] [--mode lsb|chunk]
+	// ./stegno --verify --png <image path> [--repair --to <out file>]
 
 	var (
 		pngImage string
@@ -236,6 +36,19 @@ func main() {
 		//
 		decode bool
 		dump   bool
+		//
+		password     string
+		promptPasswd bool
+		maxChunkSize int
+		//
+		mode     string
+		capacity bool
+		//
+		verify bool
+		repair bool
+		//
+		signKeyPath   string
+		verifyKeyPath string
 	)
 
 	flag.BoolVar(&encode, "encode", false, "set to true to hide the data to the png file")
@@ -246,23 +59,96 @@ func main() {
 	flag.StringVar(&message, "message", "", "set the message that you want to hide into the png file.")
 	flag.StringVar(&file, "file", "", "set the file path that you want to hide it data into the png file.")
 	flag.StringVar(&to, "to", "", "set the output file to write")
+	flag.StringVar(&password, "password", "", "set the password used to encrypt/decrypt the hidden data.")
+	flag.BoolVar(&promptPasswd, "password-stdin", false, "prompt for the password on stdin without echoing it.")
+	flag.IntVar(&maxChunkSize, "max-chunk-size", png.DefaultMaxChunkSize, "largest payload size in bytes to store per pUNK chunk before splitting.")
+	flag.StringVar(&mode, "mode", "chunk", "steganography mode to use: \"chunk\" (ancillary pUNK chunk) or \"lsb\" (IDAT pixel LSBs).")
+	flag.BoolVar(&capacity, "capacity", false, "report the maximum number of bytes embeddable in --png under --mode lsb and exit.")
+	flag.BoolVar(&verify, "verify", false, "walk every chunk in --png and report which, if any, have a corrupted CRC.")
+	flag.BoolVar(&repair, "repair", false, "recompute chunk CRCs on write; combine with --verify to fix a corrupted file.")
+	flag.StringVar(&signKeyPath, "sign-key", "", "path to an ECDSA-P256 PEM private key to sign the embedded payload with.")
+	flag.StringVar(&verifyKeyPath, "verify-key", "", "path to an ECDSA-P256 PEM public key to verify the embedded payload's signature with.")
 
 	flag.Parse()
 
+	if promptPasswd {
+		p, err := readPassword()
+		if err != nil {
+			log.Fatal(err)
+		}
+		password = p
+	}
+
 	if pngImage == "" {
 		log.Printf("no image specified")
 		flag.PrintDefaults()
 		return
 	}
 
-	png, err := readPNG(pngImage)
+	parser := png.NewParser()
+	writer := png.NewWriter()
+
+	if verify {
+		img, failures, err := parser.VerifyFile(pngImage)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if len(failures) == 0 {
+			log.Printf("[+] %s: all %d chunks verified OK", pngImage, len(img.Chunks))
+		} else {
+			for _, f := range failures {
+				log.Printf("[!] %s", f)
+			}
+			log.Printf("[!] %s: %d corrupted chunk(s) found", pngImage, len(failures))
+		}
+
+		if repair {
+			if err := writer.Repair(img); err != nil {
+				log.Fatal(err)
+			}
+			out := to
+			if out == "" {
+				out = pngImage
+			}
+			if err := writer.WriteFile(img, out); err != nil {
+				log.Fatal(err)
+			}
+			log.Printf("[+] %s repaired and written", out)
+		}
+
+		return
+	}
+
+	img, err := parser.ParseFile(pngImage)
 	if err != nil {
 		log.Fatal(err)
 	}
 
+	stego, err := newStego(mode, password, maxChunkSize, signKeyPath, verifyKeyPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if capacity {
+		lsb, ok := stego.(*png.LSBEmbedder)
+		if !ok {
+			log.Fatal("--capacity is only supported with --mode lsb")
+		}
+		max, err := lsb.Capacity(img)
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("[+] %s can hold up to %d bytes in --mode lsb", pngImage, max)
+		return
+	}
+
 	if encode {
 		if message != "" && to != "" {
-			if err := Encode(png, []byte(message), to); err != nil {
+			if err := stego.Embed(img, []byte(message)); err != nil {
+				log.Fatal(err)
+			}
+			if err := writeOut(writer, img, to, repair); err != nil {
 				log.Fatal(err)
 			}
 			log.Printf("[+] %s written", to)
@@ -274,7 +160,10 @@ func main() {
 			if err != nil {
 				log.Fatal(err)
 			}
-			if err := Encode(png, data, to); err != nil {
+			if err := stego.Embed(img, data); err != nil {
+				log.Fatal(err)
+			}
+			if err := writeOut(writer, img, to, repair); err != nil {
 				log.Fatal(err)
 			}
 
@@ -284,7 +173,7 @@ func main() {
 	}
 
 	if decode {
-		data, err := Decode(png)
+		data, err := stego.Extract(img)
 		if err != nil {
 			log.Fatal(err)
 		}
@@ -304,69 +193,61 @@ func main() {
 	flag.PrintDefaults()
 }
 
-func readPNG(filename string) (*PNG, error) {
-	fd, err := os.Open(filename)
-	if err != nil {
-		return nil, err
-	}
-	defer fd.Close()
-
-	header := new(PngHeader)
-	if err := header.ReadHeader(fd); err != nil {
-		return nil, err
-	}
-
-	ok, err := header.Validate()
-	if err != nil {
-		return nil, err
-	}
-
-	if !ok {
-		// TODO: export this error into a global variable.
-		return nil, fmt.Errorf("Not a PNG file")
-	}
-
-	png := &PNG{
-		Header: *header,
-	}
-
-	if err := png.ReadChunks(fd); err != nil {
-		return nil, err
+// writeOut marshals img to outpath, recomputing every chunk's CRC first
+// when repair is set.
+func writeOut(writer *png.Writer, img *png.Image, outpath string, repair bool) error {
+	if repair {
+		if err := writer.Repair(img); err != nil {
+			return err
+		}
 	}
-
-	return png, nil
+	return writer.WriteFile(img, outpath)
 }
 
-// TODO: we still don't encrypt the data that we are hiding
-func Encode(png *PNG, data []byte, outpngName string) error {
-	m, err := NewPngMetadata(data)
-	if err != nil {
-		return err
-	}
+// newStego builds the png.Stego implementation selected by --mode.
+func newStego(mode, password string, maxChunkSize int, signKeyPath, verifyKeyPath string) (png.Stego, error) {
+	switch mode {
+	case "lsb":
+		if password != "" {
+			return nil, fmt.Errorf("--password is not supported with --mode lsb yet")
+		}
+		if signKeyPath != "" || verifyKeyPath != "" {
+			return nil, fmt.Errorf("--sign-key/--verify-key are not supported with --mode lsb")
+		}
+		return &png.LSBEmbedder{}, nil
+	case "chunk":
+		embedder := &png.ChunkEmbedder{Password: password, MaxChunkSize: maxChunkSize}
 
-	// append the new chunk into the `len(chunks)-2`
-	// NOTE: we can change this way of appending the new chunk in the future.
-	png.Chunks = append(png.Chunks[:png.newChunkIndex+1], []*PngMetadata{m, png.Chunks[len(png.Chunks)-1]}...)
+		if signKeyPath != "" {
+			key, err := png.LoadSigningKeyFile(signKeyPath)
+			if err != nil {
+				return nil, err
+			}
+			embedder.SignKey = key
+		}
 
-	// get new png data
-	pngdata, err := png.Marshal()
-	if err != nil {
-		return err
-	}
+		if verifyKeyPath != "" {
+			key, err := png.LoadVerifyKeyFile(verifyKeyPath)
+			if err != nil {
+				return nil, err
+			}
+			embedder.VerifyKey = key
+		}
 
-	return os.WriteFile(outpngName, pngdata, 0666)
+		return embedder, nil
+	default:
+		return nil, fmt.Errorf("unknown --mode %q: must be \"chunk\" or \"lsb\"", mode)
+	}
 }
 
-func Decode(png *PNG) ([]byte, error) {
-	c := png.Chunks[png.newChunkIndex]
-	typ, err := c.Type.String()
+// readPassword prompts for a password on the controlling terminal without
+// echoing it back, used when --password-stdin is passed.
+func readPassword() (string, error) {
+	fmt.Fprint(os.Stderr, "Password: ")
+	data, err := term.ReadPassword(int(syscall.Stdin))
+	fmt.Fprintln(os.Stderr)
 	if err != nil {
-		return nil, err
-	}
-	if typ != NewChunkTypeName {
-		return nil, fmt.Errorf("couldn't find the png chunk")
+		return "", err
 	}
-
-	// TODO: we need to decrypt the data here.
-	return c.Data, nil
+	return string(data), nil
 }
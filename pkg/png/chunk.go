@@ -0,0 +1,111 @@
+package png
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+)
+
+// ChunkType is the 4-byte ASCII identifier of a PNG chunk (e.g. "IHDR").
+type ChunkType uint32
+
+// String returns the chunk type as its 4-character ASCII name.
+func (c ChunkType) String() (string, error) {
+	buf := make([]byte, 4)
+	binary.BigEndian.PutUint32(buf, uint32(c))
+	return string(buf), nil
+}
+
+// typeNameToUint32 packs a 4-character chunk type name into its big-endian
+// uint32 representation.
+func typeNameToUint32(name string) uint32 {
+	return binary.BigEndian.Uint32([]byte(name))
+}
+
+// Chunk represents a single PNG chunk: length, type, data and CRC.
+type Chunk struct {
+	Length uint32
+	Type   ChunkType
+	Data   []byte
+	CRC    uint32
+}
+
+// NewChunk creates a new chunk of the given 4-character type name over data
+// and computes its CRC.
+func NewChunk(typeName string, data []byte) (*Chunk, error) {
+	c := &Chunk{
+		Length: uint32(len(data)),
+		Type:   ChunkType(typeNameToUint32(typeName)),
+		Data:   data,
+	}
+	if err := c.generateCRC(); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// TypeString returns the chunk's type as a string, e.g. "IDAT".
+func (c *Chunk) TypeString() (string, error) {
+	return c.Type.String()
+}
+
+// computeCRC computes the CRC-32 (IEEE) over the chunk's type and data, as
+// specified by the PNG format.
+func (c *Chunk) computeCRC() (uint32, error) {
+	buffer := &bytes.Buffer{}
+	if err := binary.Write(buffer, binary.BigEndian, c.Type); err != nil {
+		return 0, err
+	}
+
+	if err := binary.Write(buffer, binary.BigEndian, c.Data); err != nil {
+		return 0, err
+	}
+
+	return crc32.ChecksumIEEE(buffer.Bytes()), nil
+}
+
+// generateCRC generates a new CRC for the chunk.
+func (c *Chunk) generateCRC() error {
+	crc, err := c.computeCRC()
+	if err != nil {
+		return err
+	}
+
+	c.CRC = crc
+	return nil
+}
+
+// VerifyCRC reports whether the chunk's stored CRC matches its type and
+// data.
+func (c *Chunk) VerifyCRC() (bool, error) {
+	crc, err := c.computeCRC()
+	if err != nil {
+		return false, err
+	}
+
+	return crc == c.CRC, nil
+}
+
+func (c *Chunk) readLength(r io.Reader) error {
+	return binary.Read(r, binary.BigEndian, &c.Length)
+}
+
+func (c *Chunk) readType(r io.Reader) error {
+	return binary.Read(r, binary.BigEndian, &c.Type)
+}
+
+func (c *Chunk) readData(r io.Reader) error {
+	buf := make([]byte, c.Length)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return err
+	}
+	c.Data = buf
+
+	return nil
+}
+
+func (c *Chunk) readCRC(r io.Reader) error {
+	return binary.Read(r, binary.BigEndian, &c.CRC)
+}
@@ -0,0 +1,88 @@
+package png
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"fmt"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// SaltChunkType is the ancillary chunk used to store the scrypt salt for an
+// encrypted payload. When present, it is inserted immediately before the
+// PayloadChunkType chunk it belongs to.
+const SaltChunkType string = "saLt"
+
+const (
+	scryptN      = 32768
+	scryptR      = 8
+	scryptP      = 1
+	scryptKeyLen = 32
+
+	saltSize = 16
+)
+
+// deriveKey derives a 32-byte AES-256 key from password and salt using
+// scrypt, mirroring the parameters used by cryptpng.
+func deriveKey(password, salt []byte) ([]byte, error) {
+	return scrypt.Key(password, salt, scryptN, scryptR, scryptP, scryptKeyLen)
+}
+
+// encryptPayload encrypts data with AES-256-GCM under a key derived from
+// password and a freshly generated salt. It returns the salt and the
+// `nonce || ciphertext || tag` blob meant to be stored in the payload chunk.
+func encryptPayload(data []byte, password string) (salt, sealed []byte, err error) {
+	salt = make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, nil, err
+	}
+
+	key, err := deriveKey([]byte(password), salt)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, nil, err
+	}
+
+	sealed = gcm.Seal(nonce, nonce, data, nil)
+	return salt, sealed, nil
+}
+
+// decryptPayload reverses encryptPayload: blob is `nonce || ciphertext || tag`.
+func decryptPayload(blob, salt []byte, password string) ([]byte, error) {
+	key, err := deriveKey([]byte(password), salt)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(blob) < gcm.NonceSize() {
+		return nil, fmt.Errorf("encrypted payload too short")
+	}
+
+	nonce, ciphertext := blob[:gcm.NonceSize()], blob[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, ciphertext, nil)
+}
@@ -0,0 +1,41 @@
+package png
+
+import "testing"
+
+func TestEncryptDecryptPayloadRoundtrip(t *testing.T) {
+	data := []byte("the quick brown fox jumps over the lazy dog")
+	password := "correct horse battery staple"
+
+	salt, sealed, err := encryptPayload(data, password)
+	if err != nil {
+		t.Fatalf("encryptPayload: %v", err)
+	}
+
+	got, err := decryptPayload(sealed, salt, password)
+	if err != nil {
+		t.Fatalf("decryptPayload: %v", err)
+	}
+
+	if string(got) != string(data) {
+		t.Fatalf("roundtrip mismatch: got %q, want %q", got, data)
+	}
+}
+
+func TestDecryptPayloadWrongPassword(t *testing.T) {
+	salt, sealed, err := encryptPayload([]byte("secret"), "right password")
+	if err != nil {
+		t.Fatalf("encryptPayload: %v", err)
+	}
+
+	if _, err := decryptPayload(sealed, salt, "wrong password"); err == nil {
+		t.Fatal("decryptPayload succeeded with the wrong password, want an error")
+	}
+}
+
+func TestDecryptPayloadTooShort(t *testing.T) {
+	salt := make([]byte, saltSize)
+
+	if _, err := decryptPayload([]byte("short"), salt, "whatever"); err == nil {
+		t.Fatal("decryptPayload succeeded on a too-short blob, want an error")
+	}
+}
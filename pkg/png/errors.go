@@ -0,0 +1,27 @@
+package png
+
+import "fmt"
+
+// ErrCrcFailure reports a chunk whose stored CRC doesn't match its type and
+// data.
+type ErrCrcFailure struct {
+	Index int
+	Type  string
+	Want  uint32
+	Got   uint32
+}
+
+func (e *ErrCrcFailure) Error() string {
+	return fmt.Sprintf("chunk %d (%s): CRC mismatch: want %08x, got %08x", e.Index, e.Type, e.Want, e.Got)
+}
+
+// ErrSignatureMismatch reports that a payload's `pSIG` chunk didn't verify
+// against the provided key, either because the signing key's fingerprint
+// doesn't match or because the ECDSA signature itself is invalid.
+type ErrSignatureMismatch struct {
+	Reason string
+}
+
+func (e *ErrSignatureMismatch) Error() string {
+	return fmt.Sprintf("signature verification failed: %s", e.Reason)
+}
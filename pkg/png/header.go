@@ -0,0 +1,38 @@
+package png
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"io"
+	"strconv"
+)
+
+// Header is the 8-byte PNG file signature.
+type Header struct {
+	Magic uint64
+}
+
+// Read reads the 8-byte PNG signature from r.
+func (h *Header) Read(r io.Reader) error {
+	return binary.Read(r, binary.BigEndian, &h.Magic)
+}
+
+// Validate reports whether the signature looks like a PNG file.
+func (h *Header) Validate() (bool, error) {
+	if h.Magic == 0 {
+		return false, nil
+	}
+
+	hexStr := strconv.FormatUint(h.Magic, 16)
+
+	data, err := hex.DecodeString(hexStr)
+	if err != nil {
+		return false, err
+	}
+
+	if len(data) < 4 {
+		return false, nil
+	}
+
+	return string(data[1:4]) == "PNG", nil
+}
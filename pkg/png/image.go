@@ -0,0 +1,249 @@
+package png
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+)
+
+// IENDType is the chunk type that always terminates a PNG stream.
+const IENDType string = "IEND"
+
+// Image represents a parsed PNG file: its header plus an ordered list of
+// chunks.
+type Image struct {
+	Header Header
+	Chunks []*Chunk
+
+	// lastChunkIndex is the index, within Chunks, of the last chunk read
+	// before IEND - i.e. where newly embedded chunks get inserted on encode.
+	lastChunkIndex int
+
+	// crcErrors accumulates every CRC mismatch found while reading Chunks.
+	crcErrors []*ErrCrcFailure
+}
+
+// Parser reads a PNG stream into an Image.
+type Parser struct{}
+
+// NewParser returns a ready-to-use Parser.
+func NewParser() *Parser {
+	return &Parser{}
+}
+
+// parse reads and validates a PNG stream into an Image, recording (but not
+// failing on) any CRC mismatches found along the way.
+func (p *Parser) parse(r io.Reader) (*Image, error) {
+	header := new(Header)
+	if err := header.Read(r); err != nil {
+		return nil, err
+	}
+
+	ok, err := header.Validate()
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("not a PNG file")
+	}
+
+	img := &Image{Header: *header}
+	if err := img.readChunks(r); err != nil {
+		return nil, err
+	}
+
+	return img, nil
+}
+
+// Parse reads and validates a PNG stream, returning its Image. It fails
+// with the first ErrCrcFailure encountered if any chunk's CRC doesn't
+// match its type and data; use Verify if you need to see every corrupted
+// chunk instead of just the first.
+func (p *Parser) Parse(r io.Reader) (*Image, error) {
+	img, err := p.parse(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(img.crcErrors) > 0 {
+		return nil, img.crcErrors[0]
+	}
+
+	return img, nil
+}
+
+// ParseFile opens filename and parses it as a PNG image.
+func (p *Parser) ParseFile(filename string) (*Image, error) {
+	fd, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer fd.Close()
+
+	return p.Parse(fd)
+}
+
+// Verify parses r like Parse, but tolerates CRC mismatches instead of
+// failing on the first one: it returns the parsed Image together with
+// every chunk that failed CRC verification.
+func (p *Parser) Verify(r io.Reader) (*Image, []*ErrCrcFailure, error) {
+	img, err := p.parse(r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return img, img.crcErrors, nil
+}
+
+// VerifyFile opens filename and verifies it like Verify.
+func (p *Parser) VerifyFile(filename string) (*Image, []*ErrCrcFailure, error) {
+	fd, err := os.Open(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer fd.Close()
+
+	return p.Verify(fd)
+}
+
+// readChunks reads every chunk off r until (and including) IEND.
+func (img *Image) readChunks(r io.Reader) error {
+	for {
+		chunk := new(Chunk)
+		if err := chunk.readLength(r); err != nil {
+			return err
+		}
+
+		if err := chunk.readType(r); err != nil {
+			return err
+		}
+
+		typ, err := chunk.TypeString()
+		if err != nil {
+			return err
+		}
+
+		if err := chunk.readData(r); err != nil {
+			return err
+		}
+
+		if err := chunk.readCRC(r); err != nil {
+			return err
+		}
+
+		index := len(img.Chunks)
+		img.Chunks = append(img.Chunks, chunk)
+
+		ok, err := chunk.VerifyCRC()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			want, err := chunk.computeCRC()
+			if err != nil {
+				return err
+			}
+			img.crcErrors = append(img.crcErrors, &ErrCrcFailure{
+				Index: index,
+				Type:  typ,
+				Want:  want,
+				Got:   chunk.CRC,
+			})
+		}
+
+		if typ == IENDType {
+			img.lastChunkIndex = len(img.Chunks) - 2
+			if img.lastChunkIndex < 0 {
+				img.lastChunkIndex = 0
+			}
+			return nil
+		}
+	}
+}
+
+// InsertBeforeIEND splices newChunks into the image right before IEND, in
+// the order given. This is the extension point every Stego implementation
+// uses to add its own chunks.
+func (img *Image) InsertBeforeIEND(newChunks []*Chunk) {
+	iend := img.Chunks[len(img.Chunks)-1]
+	img.Chunks = append(img.Chunks[:img.lastChunkIndex+1], append(newChunks, iend)...)
+}
+
+// Chunks iterates over the image's chunks, visitor-style, stopping early if
+// visit returns an error.
+func (img *Image) Walk(visit func(i int, c *Chunk) error) error {
+	for i, c := range img.Chunks {
+		if err := visit(i, c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Print writes a human-readable summary of every chunk to w.
+func (img *Image) Print(w io.Writer) {
+	for _, c := range img.Chunks {
+		typ, _ := c.TypeString()
+		fmt.Fprintf(w, "--------------\n")
+		fmt.Fprintf(w, "Length %v\n", c.Length)
+		fmt.Fprintf(w, "Type %v\n", typ)
+		fmt.Fprintf(w, "CRC %v\n", c.CRC)
+	}
+}
+
+// Writer serializes an Image back into PNG bytes.
+type Writer struct{}
+
+// NewWriter returns a ready-to-use Writer.
+func NewWriter() *Writer {
+	return &Writer{}
+}
+
+// Marshal serializes img back into PNG bytes.
+func (w *Writer) Marshal(img *Image) ([]byte, error) {
+	buf := new(bytes.Buffer)
+
+	if err := binary.Write(buf, binary.BigEndian, img.Header.Magic); err != nil {
+		return nil, err
+	}
+
+	for _, c := range img.Chunks {
+		if err := binary.Write(buf, binary.BigEndian, c.Length); err != nil {
+			return nil, err
+		}
+		if err := binary.Write(buf, binary.BigEndian, uint32(c.Type)); err != nil {
+			return nil, err
+		}
+		if err := binary.Write(buf, binary.BigEndian, c.Data); err != nil {
+			return nil, err
+		}
+		if err := binary.Write(buf, binary.BigEndian, c.CRC); err != nil {
+			return nil, err
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Repair recomputes every chunk's CRC in place, fixing any corruption
+// reported by Verify.
+func (w *Writer) Repair(img *Image) error {
+	for _, c := range img.Chunks {
+		if err := c.generateCRC(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteFile marshals img and writes it to filename.
+func (w *Writer) WriteFile(img *Image, filename string) error {
+	data, err := w.Marshal(img)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filename, data, 0666)
+}
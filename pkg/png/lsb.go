@@ -0,0 +1,352 @@
+package png
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// IHDRType and IDATType are the critical PNG chunks LSB mode reads and
+// rewrites.
+const IHDRType string = "IHDR"
+const IDATType string = "IDAT"
+
+// lsbLengthHeaderSize is the number of bits reserved at the start of the
+// embedded stream to record the payload length in bytes.
+const lsbLengthHeaderSize = 32
+
+// ihdrInfo holds the handful of IHDR fields LSB mode cares about.
+type ihdrInfo struct {
+	Width     uint32
+	Height    uint32
+	BitDepth  uint8
+	ColorType uint8
+	Interlace uint8
+}
+
+// parseIHDR finds and decodes the IHDR chunk of img.
+func parseIHDR(img *Image) (*ihdrInfo, error) {
+	for _, c := range img.Chunks {
+		typ, err := c.TypeString()
+		if err != nil {
+			return nil, err
+		}
+
+		if typ != IHDRType {
+			continue
+		}
+
+		if len(c.Data) < 13 {
+			return nil, fmt.Errorf("invalid IHDR chunk")
+		}
+
+		ihdr := &ihdrInfo{
+			Width:     binary.BigEndian.Uint32(c.Data[0:4]),
+			Height:    binary.BigEndian.Uint32(c.Data[4:8]),
+			BitDepth:  c.Data[8],
+			ColorType: c.Data[9],
+			Interlace: c.Data[12],
+		}
+		if ihdr.Interlace != 0 {
+			return nil, fmt.Errorf("LSB mode only supports non-interlaced PNGs, got interlace method %d", ihdr.Interlace)
+		}
+
+		return ihdr, nil
+	}
+
+	return nil, fmt.Errorf("couldn't find the IHDR chunk")
+}
+
+// channelsForColorType returns the number of color channels (bytes per
+// pixel at 8-bit depth) for the PNG color types LSB mode supports.
+func channelsForColorType(colorType uint8) (int, error) {
+	switch colorType {
+	case 0:
+		return 1, nil // grayscale
+	case 2:
+		return 3, nil // RGB
+	case 4:
+		return 2, nil // grayscale + alpha
+	case 6:
+		return 4, nil // RGBA
+	default:
+		return 0, fmt.Errorf("unsupported PNG color type %d for LSB mode", colorType)
+	}
+}
+
+// paethPredictor implements the PNG Paeth filter predictor.
+func paethPredictor(a, b, c byte) byte {
+	p := int(a) + int(b) - int(c)
+	pa, pb, pc := abs(p-int(a)), abs(p-int(b)), abs(p-int(c))
+	if pa <= pb && pa <= pc {
+		return a
+	}
+	if pb <= pc {
+		return b
+	}
+	return c
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// defilterScanlines reverses PNG scanline filtering (types 0-4), returning
+// the raw, unfiltered pixel bytes for an 8-bit-depth image of the given
+// width/height/bytes-per-pixel.
+func defilterScanlines(data []byte, width, height, bpp int) ([]byte, error) {
+	stride := width*bpp + 1
+	if len(data) < stride*height {
+		return nil, fmt.Errorf("not enough scanline data for the declared image dimensions")
+	}
+
+	out := make([]byte, width*bpp*height)
+	prevRow := make([]byte, width*bpp)
+
+	for y := 0; y < height; y++ {
+		rowStart := y * stride
+		filterType := data[rowStart]
+		raw := data[rowStart+1 : rowStart+stride]
+		row := make([]byte, width*bpp)
+
+		for x := range raw {
+			var a, b, c byte
+			if x >= bpp {
+				a = row[x-bpp]
+				c = prevRow[x-bpp]
+			}
+			b = prevRow[x]
+
+			switch filterType {
+			case 0:
+				row[x] = raw[x]
+			case 1:
+				row[x] = raw[x] + a
+			case 2:
+				row[x] = raw[x] + b
+			case 3:
+				row[x] = raw[x] + byte((int(a)+int(b))/2)
+			case 4:
+				row[x] = raw[x] + paethPredictor(a, b, c)
+			default:
+				return nil, fmt.Errorf("unsupported PNG filter type %d", filterType)
+			}
+		}
+
+		copy(out[y*width*bpp:], row)
+		prevRow = row
+	}
+
+	return out, nil
+}
+
+// filterScanlinesNone re-applies PNG filter type 0 (None) to raw pixel
+// bytes, prefixing each scanline with its filter-type byte.
+func filterScanlinesNone(pixels []byte, width, height, bpp int) []byte {
+	stride := width * bpp
+	out := make([]byte, 0, (stride+1)*height)
+
+	for y := 0; y < height; y++ {
+		out = append(out, 0)
+		out = append(out, pixels[y*stride:(y+1)*stride]...)
+	}
+
+	return out
+}
+
+// lsbCapacity returns the maximum number of payload bytes that fit in
+// pixelByteCount color bytes, after reserving the length header.
+func lsbCapacity(pixelByteCount int) int {
+	bits := pixelByteCount - lsbLengthHeaderSize
+	if bits < 0 {
+		return 0
+	}
+	return bits / 8
+}
+
+// embedLSB hides payload in the LSBs of pixels: a 32-bit big-endian length
+// header followed by the payload bits.
+func embedLSB(pixels, payload []byte) error {
+	capacity := lsbCapacity(len(pixels))
+	if len(payload) > capacity {
+		return fmt.Errorf("payload too large for LSB mode: capacity is %d bytes, got %d", capacity, len(payload))
+	}
+
+	header := make([]byte, 4)
+	binary.BigEndian.PutUint32(header, uint32(len(payload)))
+
+	bits := bytesToBits(append(header, payload...))
+	for i, bit := range bits {
+		pixels[i] = (pixels[i] &^ 1) | bit
+	}
+
+	return nil
+}
+
+// extractLSB reverses embedLSB.
+func extractLSB(pixels []byte) ([]byte, error) {
+	if len(pixels) < lsbLengthHeaderSize {
+		return nil, fmt.Errorf("image too small to contain an LSB length header")
+	}
+
+	headerBits := make([]byte, lsbLengthHeaderSize)
+	for i := range headerBits {
+		headerBits[i] = pixels[i] & 1
+	}
+	length := binary.BigEndian.Uint32(bitsToBytes(headerBits))
+
+	needed := lsbLengthHeaderSize + int(length)*8
+	if needed > len(pixels) {
+		return nil, fmt.Errorf("embedded length exceeds image capacity, image may be corrupted")
+	}
+
+	payloadBits := make([]byte, int(length)*8)
+	for i := range payloadBits {
+		payloadBits[i] = pixels[lsbLengthHeaderSize+i] & 1
+	}
+
+	return bitsToBytes(payloadBits), nil
+}
+
+func bytesToBits(b []byte) []byte {
+	bits := make([]byte, len(b)*8)
+	for i, by := range b {
+		for j := 0; j < 8; j++ {
+			bits[i*8+j] = (by >> (7 - j)) & 1
+		}
+	}
+	return bits
+}
+
+func bitsToBytes(bits []byte) []byte {
+	out := make([]byte, len(bits)/8)
+	for i := range out {
+		var b byte
+		for j := 0; j < 8; j++ {
+			b = (b << 1) | bits[i*8+j]
+		}
+		out[i] = b
+	}
+	return out
+}
+
+// collectIDAT concatenates every IDAT chunk's data in order and returns the
+// indices of those chunks within img.Chunks.
+func collectIDAT(img *Image) ([]byte, []int, error) {
+	buf := new(bytes.Buffer)
+	indices := []int{}
+
+	for i, c := range img.Chunks {
+		typ, err := c.TypeString()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if typ == IDATType {
+			indices = append(indices, i)
+			buf.Write(c.Data)
+		}
+	}
+
+	if len(indices) == 0 {
+		return nil, nil, fmt.Errorf("couldn't find any IDAT chunk")
+	}
+
+	return buf.Bytes(), indices, nil
+}
+
+// rebuildIDAT replaces the IDAT chunks at indices with new ones carrying
+// data, sized like the original first IDAT chunk so strict parsers that
+// reject oversized chunks keep working.
+func rebuildIDAT(img *Image, indices []int, data []byte) error {
+	unitSize := len(img.Chunks[indices[0]].Data)
+	if unitSize == 0 {
+		unitSize = len(data)
+	}
+	if unitSize == 0 {
+		unitSize = 1
+	}
+
+	newChunks := make([]*Chunk, 0, len(data)/unitSize+1)
+	for off := 0; off < len(data); off += unitSize {
+		end := off + unitSize
+		if end > len(data) {
+			end = len(data)
+		}
+
+		c, err := NewChunk(IDATType, data[off:end])
+		if err != nil {
+			return err
+		}
+		newChunks = append(newChunks, c)
+	}
+
+	first, last := indices[0], indices[len(indices)-1]
+	tail := append([]*Chunk{}, img.Chunks[last+1:]...)
+	img.Chunks = append(append(img.Chunks[:first], newChunks...), tail...)
+
+	return nil
+}
+
+// inflate zlib-decompresses data.
+func inflate(data []byte) ([]byte, error) {
+	zr, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	return io.ReadAll(zr)
+}
+
+// deflate zlib-compresses data.
+func deflate(data []byte) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	zw := zlib.NewWriter(buf)
+	if _, err := zw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// lsbPixels inflates and un-filters img's IDAT data down to raw pixel bytes.
+func lsbPixels(img *Image) ([]byte, *ihdrInfo, int, error) {
+	ihdr, err := parseIHDR(img)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	if ihdr.BitDepth != 8 {
+		return nil, nil, 0, fmt.Errorf("LSB mode only supports 8-bit PNGs, got bit depth %d", ihdr.BitDepth)
+	}
+
+	bpp, err := channelsForColorType(ihdr.ColorType)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	idatData, _, err := collectIDAT(img)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	raw, err := inflate(idatData)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	pixels, err := defilterScanlines(raw, int(ihdr.Width), int(ihdr.Height), bpp)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+
+	return pixels, ihdr, bpp, nil
+}
@@ -0,0 +1,132 @@
+package png
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+)
+
+// SignatureChunkType is the ancillary chunk used to store a detached
+// ECDSA-P256 signature over a (possibly encrypted) payload, plus a SHA-256
+// fingerprint of the signing key. It is placed adjacent to the payload
+// chunk(s) it signs.
+const SignatureChunkType string = "pSIG"
+
+// fingerprintSize is the length, in bytes, of the SHA-256 key fingerprint
+// stored at the front of a `pSIG` chunk.
+const fingerprintSize = 32
+
+// keyFingerprint returns the SHA-256 digest of pub's DER-encoded form, used
+// to let a verifier confirm a signature was made with the key it expects.
+func keyFingerprint(pub *ecdsa.PublicKey) ([]byte, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(der)
+	return sum[:], nil
+}
+
+// signPayload computes the SHA-256 digest of data and signs it with priv
+// (ECDSA-P256), returning the signing key's fingerprint alongside the
+// DER-encoded signature.
+func signPayload(data []byte, priv *ecdsa.PrivateKey) (fingerprint, signature []byte, err error) {
+	digest := sha256.Sum256(data)
+
+	signature, err = ecdsa.SignASN1(rand.Reader, priv, digest[:])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	fingerprint, err = keyFingerprint(&priv.PublicKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return fingerprint, signature, nil
+}
+
+// verifyPayloadSignature checks a `pSIG` chunk's data (fingerprint ||
+// signature) against data and pub, returning an *ErrSignatureMismatch if
+// either the fingerprint or the signature doesn't match.
+func verifyPayloadSignature(data, sigChunkData []byte, pub *ecdsa.PublicKey) error {
+	if len(sigChunkData) < fingerprintSize {
+		return &ErrSignatureMismatch{Reason: "pSIG chunk is too short to hold a fingerprint"}
+	}
+
+	fingerprint, signature := sigChunkData[:fingerprintSize], sigChunkData[fingerprintSize:]
+
+	wantFingerprint, err := keyFingerprint(pub)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(fingerprint, wantFingerprint) {
+		return &ErrSignatureMismatch{Reason: "signing key fingerprint does not match --verify-key"}
+	}
+
+	digest := sha256.Sum256(data)
+	if !ecdsa.VerifyASN1(pub, digest[:], signature) {
+		return &ErrSignatureMismatch{Reason: "signature does not match payload"}
+	}
+
+	return nil
+}
+
+// LoadSigningKeyFile reads an ECDSA-P256 private key from a PEM file, for
+// use as ChunkEmbedder.SignKey.
+func LoadSigningKeyFile(pemPath string) (*ecdsa.PrivateKey, error) {
+	data, err := os.ReadFile(pemPath)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: not a PEM file", pemPath)
+	}
+
+	key, err := x509.ParseECPrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	if key.Curve != elliptic.P256() {
+		return nil, fmt.Errorf("%s: signing key must be ECDSA-P256", pemPath)
+	}
+
+	return key, nil
+}
+
+// LoadVerifyKeyFile reads an ECDSA-P256 public key from a PEM file, for use
+// as ChunkEmbedder.VerifyKey.
+func LoadVerifyKeyFile(pemPath string) (*ecdsa.PublicKey, error) {
+	data, err := os.ReadFile(pemPath)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: not a PEM file", pemPath)
+	}
+
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	ecdsaPub, ok := pub.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("%s: not an ECDSA public key", pemPath)
+	}
+	if ecdsaPub.Curve != elliptic.P256() {
+		return nil, fmt.Errorf("%s: verify key must be ECDSA-P256", pemPath)
+	}
+
+	return ecdsaPub, nil
+}
@@ -0,0 +1,77 @@
+package png
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"testing"
+)
+
+func TestSignVerifyPayloadSignatureRoundtrip(t *testing.T) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	data := []byte("payload to sign")
+	fingerprint, signature, err := signPayload(data, priv)
+	if err != nil {
+		t.Fatalf("signPayload: %v", err)
+	}
+
+	sigChunkData := append(append([]byte{}, fingerprint...), signature...)
+	if err := verifyPayloadSignature(data, sigChunkData, &priv.PublicKey); err != nil {
+		t.Fatalf("verifyPayloadSignature: %v", err)
+	}
+}
+
+func TestVerifyPayloadSignatureWrongKey(t *testing.T) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+	other, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	data := []byte("payload to sign")
+	fingerprint, signature, err := signPayload(data, priv)
+	if err != nil {
+		t.Fatalf("signPayload: %v", err)
+	}
+
+	sigChunkData := append(append([]byte{}, fingerprint...), signature...)
+	if err := verifyPayloadSignature(data, sigChunkData, &other.PublicKey); err == nil {
+		t.Fatal("verifyPayloadSignature succeeded with the wrong key, want an error")
+	}
+}
+
+func TestVerifyPayloadSignatureTamperedData(t *testing.T) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	data := []byte("payload to sign")
+	fingerprint, signature, err := signPayload(data, priv)
+	if err != nil {
+		t.Fatalf("signPayload: %v", err)
+	}
+
+	sigChunkData := append(append([]byte{}, fingerprint...), signature...)
+	if err := verifyPayloadSignature([]byte("tampered payload"), sigChunkData, &priv.PublicKey); err == nil {
+		t.Fatal("verifyPayloadSignature succeeded on tampered data, want an error")
+	}
+}
+
+func TestVerifyPayloadSignatureTruncatedChunk(t *testing.T) {
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("GenerateKey: %v", err)
+	}
+
+	if err := verifyPayloadSignature([]byte("data"), []byte("short"), &priv.PublicKey); err == nil {
+		t.Fatal("verifyPayloadSignature succeeded on a too-short pSIG chunk, want an error")
+	}
+}
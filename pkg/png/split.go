@@ -0,0 +1,134 @@
+package png
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// PayloadChunkType is the ancillary chunk used to carry (possibly split)
+// hidden payload data.
+const PayloadChunkType string = "pUNK"
+
+// splitMagic identifies a PayloadChunkType chunk as part of a sequence
+// produced by splitPayload, as opposed to a single, unsplit legacy chunk.
+const splitMagic uint32 = 0x53504c54 // "SPLT"
+
+// DefaultMaxChunkSize is the largest payload stuffed into a single
+// PayloadChunkType chunk before splitting it across several, matching the
+// size some strict PNG viewers start rejecting ancillary chunks at.
+const DefaultMaxChunkSize = 1 << 20 // 1 MiB
+
+// chunkHeaderSize is the marshaled size of chunkHeader: four uint32 fields.
+const chunkHeaderSize = 16
+
+// chunkHeader is prefixed to every PayloadChunkType chunk's Data so the
+// payload can be reassembled after being split across several chunks.
+type chunkHeader struct {
+	Magic    uint32
+	TotalLen uint32
+	Index    uint32
+	Count    uint32
+}
+
+func (h chunkHeader) marshal() ([]byte, error) {
+	buf := new(bytes.Buffer)
+	if err := binary.Write(buf, binary.BigEndian, h); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// parseChunkHeader reads a chunkHeader off the front of data and returns it
+// along with the remaining payload bytes.
+func parseChunkHeader(data []byte) (chunkHeader, []byte, error) {
+	var h chunkHeader
+	if len(data) < chunkHeaderSize {
+		return h, nil, fmt.Errorf("pUNK chunk too short to hold a chunk header")
+	}
+	if err := binary.Read(bytes.NewReader(data[:chunkHeaderSize]), binary.BigEndian, &h); err != nil {
+		return h, nil, err
+	}
+	if h.Magic != splitMagic {
+		return h, nil, fmt.Errorf("pUNK chunk has an invalid magic")
+	}
+	return h, data[chunkHeaderSize:], nil
+}
+
+// splitPayload chunks data into one or more PayloadChunkType chunks, each
+// prefixed with a chunkHeader, so that no single chunk exceeds maxChunkSize
+// bytes. A maxChunkSize <= 0 falls back to DefaultMaxChunkSize.
+func splitPayload(data []byte, maxChunkSize int) ([]*Chunk, error) {
+	if maxChunkSize <= 0 {
+		maxChunkSize = DefaultMaxChunkSize
+	}
+
+	count := (len(data) + maxChunkSize - 1) / maxChunkSize
+	if count == 0 {
+		count = 1
+	}
+
+	chunks := make([]*Chunk, 0, count)
+	for i := 0; i < count; i++ {
+		start := i * maxChunkSize
+		end := start + maxChunkSize
+		if end > len(data) {
+			end = len(data)
+		}
+
+		header, err := chunkHeader{
+			Magic:    splitMagic,
+			TotalLen: uint32(len(data)),
+			Index:    uint32(i),
+			Count:    uint32(count),
+		}.marshal()
+		if err != nil {
+			return nil, err
+		}
+
+		c, err := NewChunk(PayloadChunkType, append(header, data[start:end]...))
+		if err != nil {
+			return nil, err
+		}
+		chunks = append(chunks, c)
+	}
+
+	return chunks, nil
+}
+
+// joinPayload reverses splitPayload: it walks payloadChunks in order,
+// validates that they belong to the same sequence, and concatenates their
+// data back into the original payload.
+func joinPayload(payloadChunks []*Chunk) ([]byte, error) {
+	if len(payloadChunks) == 0 {
+		return nil, fmt.Errorf("couldn't find the png chunk")
+	}
+
+	var first chunkHeader
+	payload := make([]byte, 0, len(payloadChunks[0].Data)*len(payloadChunks))
+
+	for i, c := range payloadChunks {
+		header, data, err := parseChunkHeader(c.Data)
+		if err != nil {
+			return nil, err
+		}
+
+		if i == 0 {
+			first = header
+		} else if header.TotalLen != first.TotalLen || header.Count != first.Count {
+			return nil, fmt.Errorf("pUNK chunk %d doesn't belong to the same sequence", i)
+		}
+
+		if header.Index != uint32(i) {
+			return nil, fmt.Errorf("pUNK chunk out of order: expected index %d, got %d", i, header.Index)
+		}
+
+		payload = append(payload, data...)
+	}
+
+	if uint32(len(payload)) != first.TotalLen {
+		return nil, fmt.Errorf("reassembled payload length %d doesn't match expected %d", len(payload), first.TotalLen)
+	}
+
+	return payload, nil
+}
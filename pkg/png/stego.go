@@ -0,0 +1,191 @@
+package png
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+)
+
+// Stego embeds and extracts an arbitrary payload inside a PNG Image.
+type Stego interface {
+	// Embed hides data inside img, mutating its chunks.
+	Embed(img *Image, data []byte) error
+	// Extract recovers the payload previously hidden by Embed.
+	Extract(img *Image) ([]byte, error)
+}
+
+// ChunkEmbedder hides payloads in one or more ancillary `pUNK` chunks,
+// optionally encrypting them with a password-derived AES-256-GCM key and
+// splitting them across several chunks once they exceed MaxChunkSize.
+type ChunkEmbedder struct {
+	// Password, when non-empty, is used to encrypt the payload before it is
+	// embedded, and is required again on Extract to decrypt it.
+	Password string
+	// MaxChunkSize is the largest payload to store per `pUNK` chunk before
+	// splitting it across several. A value <= 0 uses DefaultMaxChunkSize.
+	MaxChunkSize int
+	// SignKey, when set, signs the embedded payload with ECDSA-P256 and
+	// stores the signature in a `pSIG` chunk.
+	SignKey *ecdsa.PrivateKey
+	// VerifyKey, when set, requires a `pSIG` chunk on Extract and verifies
+	// it was signed by the matching private key.
+	VerifyKey *ecdsa.PublicKey
+}
+
+var _ Stego = (*ChunkEmbedder)(nil)
+
+// Embed hides data inside img as one or more new `pUNK` chunks. When
+// Password is set, a `saLt` chunk carrying the scrypt salt is inserted
+// immediately before them.
+func (e *ChunkEmbedder) Embed(img *Image, data []byte) error {
+	newChunks := []*Chunk{}
+	payload := data
+
+	if e.Password != "" {
+		salt, sealed, err := encryptPayload(data, e.Password)
+		if err != nil {
+			return err
+		}
+
+		saltChunk, err := NewChunk(SaltChunkType, salt)
+		if err != nil {
+			return err
+		}
+
+		newChunks = append(newChunks, saltChunk)
+		payload = sealed
+	}
+
+	payloadChunks, err := splitPayload(payload, e.MaxChunkSize)
+	if err != nil {
+		return err
+	}
+	newChunks = append(newChunks, payloadChunks...)
+
+	if e.SignKey != nil {
+		fingerprint, signature, err := signPayload(payload, e.SignKey)
+		if err != nil {
+			return err
+		}
+
+		sigChunk, err := NewChunk(SignatureChunkType, append(fingerprint, signature...))
+		if err != nil {
+			return err
+		}
+
+		newChunks = append(newChunks, sigChunk)
+	}
+
+	img.InsertBeforeIEND(newChunks)
+	return nil
+}
+
+// Extract reassembles the hidden data from every `pUNK` chunk in img, in
+// order. If a `saLt` chunk is present, the reassembled data is decrypted
+// with Password; otherwise it is returned as-is.
+func (e *ChunkEmbedder) Extract(img *Image) ([]byte, error) {
+	var saltData, sigData []byte
+	payloadChunks := make([]*Chunk, 0)
+
+	if err := img.Walk(func(_ int, c *Chunk) error {
+		typ, err := c.TypeString()
+		if err != nil {
+			return err
+		}
+
+		switch typ {
+		case SaltChunkType:
+			saltData = c.Data
+		case PayloadChunkType:
+			payloadChunks = append(payloadChunks, c)
+		case SignatureChunkType:
+			sigData = c.Data
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	payload, err := joinPayload(payloadChunks)
+	if err != nil {
+		return nil, err
+	}
+
+	if e.VerifyKey != nil {
+		if sigData == nil {
+			return nil, &ErrSignatureMismatch{Reason: "no pSIG chunk found"}
+		}
+		if err := verifyPayloadSignature(payload, sigData, e.VerifyKey); err != nil {
+			return nil, err
+		}
+	}
+
+	if saltData == nil {
+		return payload, nil
+	}
+
+	if e.Password == "" {
+		return nil, fmt.Errorf("payload is encrypted, a password is required")
+	}
+
+	return decryptPayload(payload, saltData, e.Password)
+}
+
+// LSBEmbedder hides payloads in the least-significant bits of an image's
+// IDAT pixel bytes, so the payload survives tools that strip unknown
+// ancillary chunks. Only non-interlaced, 8-bit-depth images are supported.
+type LSBEmbedder struct{}
+
+var _ Stego = (*LSBEmbedder)(nil)
+
+// Embed hides data in the LSBs of img's pixel bytes and rewrites its IDAT
+// chunks accordingly.
+func (e *LSBEmbedder) Embed(img *Image, data []byte) error {
+	pixels, ihdr, bpp, err := lsbPixels(img)
+	if err != nil {
+		return err
+	}
+
+	if err := embedLSB(pixels, data); err != nil {
+		return err
+	}
+
+	filtered := filterScanlinesNone(pixels, int(ihdr.Width), int(ihdr.Height), bpp)
+
+	deflated, err := deflate(filtered)
+	if err != nil {
+		return err
+	}
+
+	_, idatIndices, err := collectIDAT(img)
+	if err != nil {
+		return err
+	}
+
+	return rebuildIDAT(img, idatIndices, deflated)
+}
+
+// Extract reverses Embed.
+func (e *LSBEmbedder) Extract(img *Image) ([]byte, error) {
+	pixels, _, _, err := lsbPixels(img)
+	if err != nil {
+		return nil, err
+	}
+
+	return extractLSB(pixels)
+}
+
+// Capacity reports the maximum number of bytes Embed can hide in img given
+// its current dimensions and color type.
+func (e *LSBEmbedder) Capacity(img *Image) (int, error) {
+	ihdr, err := parseIHDR(img)
+	if err != nil {
+		return 0, err
+	}
+
+	bpp, err := channelsForColorType(ihdr.ColorType)
+	if err != nil {
+		return 0, err
+	}
+
+	return lsbCapacity(int(ihdr.Width) * int(ihdr.Height) * bpp), nil
+}